@@ -0,0 +1,274 @@
+// Copyright 2010 Simon Lipp.
+// Distributed under a BSD-like license. See COPYING for more
+// details
+
+package maildir
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	paths "path"
+	"strings"
+	"time"
+)
+
+// Archiver writes messages exported from a maildir to some archive format.
+type Archiver interface {
+	// Create starts a new entry named name with the given size and
+	// modification time, and returns a writer for its content.
+	Create(name string, size int64, mtime time.Time) (io.WriteCloser, error)
+	Close() error
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// TarArchiver is an Archiver that writes messages to a tar stream.
+type TarArchiver struct {
+	w *tar.Writer
+}
+
+// NewTarArchiver returns a TarArchiver writing to w.
+func NewTarArchiver(w io.Writer) *TarArchiver {
+	return &TarArchiver{w: tar.NewWriter(w)}
+}
+
+func (a *TarArchiver) Create(name string, size int64, mtime time.Time) (io.WriteCloser, error) {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    size,
+		ModTime: mtime,
+	}
+	if err := a.w.WriteHeader(hdr); err != nil {
+		return nil, err
+	}
+	return nopWriteCloser{a.w}, nil
+}
+
+func (a *TarArchiver) Close() error {
+	return a.w.Close()
+}
+
+// ZipArchiver is an Archiver that writes messages to a zip stream.
+type ZipArchiver struct {
+	w *zip.Writer
+}
+
+// NewZipArchiver returns a ZipArchiver writing to w.
+func NewZipArchiver(w io.Writer) *ZipArchiver {
+	return &ZipArchiver{w: zip.NewWriter(w)}
+}
+
+func (a *ZipArchiver) Create(name string, size int64, mtime time.Time) (io.WriteCloser, error) {
+	hdr := &zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: mtime,
+	}
+	fw, err := a.w.CreateHeader(hdr)
+	if err != nil {
+		return nil, err
+	}
+	return nopWriteCloser{fw}, nil
+}
+
+func (a *ZipArchiver) Close() error {
+	return a.w.Close()
+}
+
+// Export writes every message in m to a, under a path of the form
+// "<folder>/{cur,new}/<name>", where <folder> is the empty string for m
+// itself. If recursive is true, every sub-folder found by Container.Walk is
+// exported as well, nested under its own <folder>.
+func (m *Maildir) Export(a Archiver, recursive bool) error {
+	if err := exportMessages(m, "", a); err != nil {
+		return err
+	}
+	if !recursive {
+		return nil
+	}
+
+	return NewContainer(m).Walk(func(child *Maildir) error {
+		folder := strings.TrimPrefix(child.Path, m.Path)
+		return exportMessages(child, folder, a)
+	})
+}
+
+func exportMessages(m *Maildir, folder string, a Archiver) error {
+	for _, subdir := range []string{"cur", "new"} {
+		names, err := m.List(subdir)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if err := exportMessage(m, folder, subdir, name, a); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func exportMessage(m *Maildir, folder, subdir, name string, a Archiver) error {
+	path := paths.Join(m.Path, subdir, name)
+
+	fi, err := m.fs.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	src, err := m.fs.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := a.Create(paths.Join(folder, subdir, name), fi.Size(), fi.ModTime())
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// ArchiveReader reads back entries previously written by an Archiver. Next
+// returns io.EOF once every entry has been read.
+type ArchiveReader interface {
+	Next() (name string, mtime time.Time, r io.Reader, err error)
+}
+
+// TarReader is an ArchiveReader reading from a tar stream.
+type TarReader struct {
+	r *tar.Reader
+}
+
+// NewTarReader returns a TarReader reading from r.
+func NewTarReader(r io.Reader) *TarReader {
+	return &TarReader{r: tar.NewReader(r)}
+}
+
+func (t *TarReader) Next() (string, time.Time, io.Reader, error) {
+	hdr, err := t.r.Next()
+	if err != nil {
+		return "", time.Time{}, nil, err
+	}
+	return hdr.Name, hdr.ModTime, t.r, nil
+}
+
+// ZipReader is an ArchiveReader reading from a zip archive.
+type ZipReader struct {
+	files []*zip.File
+	idx   int
+}
+
+// NewZipReader returns a ZipReader reading the zip archive in r, which is
+// size bytes long.
+func NewZipReader(r io.ReaderAt, size int64) (*ZipReader, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return &ZipReader{files: zr.File}, nil
+}
+
+func (z *ZipReader) Next() (string, time.Time, io.Reader, error) {
+	if z.idx >= len(z.files) {
+		return "", time.Time{}, nil, io.EOF
+	}
+	f := z.files[z.idx]
+	z.idx++
+
+	rc, err := f.Open()
+	if err != nil {
+		return "", time.Time{}, nil, err
+	}
+	return f.Name, f.Modified, rc, nil
+}
+
+// Import reads back every entry from a, restoring the folder, new/cur
+// subdirectory and file name it was exported with.
+func (m *Maildir) Import(a ArchiveReader) error {
+	dirPerm := os.FileMode(m.perm | ((m.perm & 0444) >> 2))
+
+	for {
+		name, mtime, r, err := a.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		folder, subdir, base := splitExportName(name)
+		if subdir != "cur" && subdir != "new" {
+			continue
+		}
+
+		dir := paths.Join(m.Path, folder, subdir)
+		if err := checkContained(m.Path, dir); err != nil {
+			return err
+		}
+
+		folderDir := paths.Join(m.Path, folder)
+		if err := m.fs.MkdirAll(folderDir, dirPerm); err != nil {
+			return err
+		}
+		if err := createSubFolders(m.fs, folderDir, dirPerm, m.uid, m.gid); err != nil {
+			return err
+		}
+
+		path := paths.Join(dir, base)
+		if err := checkContained(m.Path, path); err != nil {
+			return err
+		}
+
+		dst, err := m.fs.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, m.perm)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(dst, r); err != nil {
+			dst.Close()
+			return err
+		}
+		if err := dst.Close(); err != nil {
+			return err
+		}
+		if err := m.fs.Chtimes(path, mtime, mtime); err != nil {
+			return err
+		}
+	}
+}
+
+// checkContained returns an error if path, once cleaned, would fall outside
+// root. It guards Import against archive entries such as "../../etc/passwd"
+// (zip-slip) escaping the maildir root.
+func checkContained(root, path string) error {
+	root = paths.Clean(root)
+	cleaned := paths.Clean(path)
+	if cleaned != root && !strings.HasPrefix(cleaned, root+"/") {
+		return fmt.Errorf("maildir: archive entry %q escapes %q", path, root)
+	}
+	return nil
+}
+
+// splitExportName splits an entry name of the form "<folder>/{cur,new}/<base>"
+// (folder may be empty) produced by Export.
+func splitExportName(name string) (folder, subdir, base string) {
+	parts := strings.Split(name, "/")
+	if len(parts) < 2 {
+		return "", "", name
+	}
+	base = parts[len(parts)-1]
+	subdir = parts[len(parts)-2]
+	folder = strings.Join(parts[:len(parts)-2], "/")
+	return folder, subdir, base
+}
@@ -0,0 +1,85 @@
+package maildir
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestExportImportTar(t *testing.T) {
+	if err := os.RemoveAll("_obj/Maildir"); err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll("_obj/Maildir")
+
+	src, err := New("_obj/Maildir/src", true)
+	if err != nil {
+		t.Fatalf("Can't create maildir: %v", err)
+	}
+
+	testData := []byte("Hello, world !")
+	if _, err := src.CreateMail(bytes.NewBuffer(testData)); err != nil {
+		t.Fatalf("Can't create mail: %v", err)
+	}
+
+	var buf bytes.Buffer
+	archiver := NewTarArchiver(&buf)
+	if err := src.Export(archiver, false); err != nil {
+		t.Fatalf("Can't export maildir: %v", err)
+	}
+	if err := archiver.Close(); err != nil {
+		t.Fatalf("Can't close archiver: %v", err)
+	}
+
+	// Sanity check: the tar stream must contain exactly one entry, under new/
+	tr := tar.NewReader(bytes.NewReader(buf.Bytes()))
+	count := 0
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Can't read tar entry: %v", err)
+		}
+		if hdr.Name[:len("new/")] != "new/" {
+			t.Errorf("Expected entry under new/, got %v", hdr.Name)
+		}
+		count++
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 entry in the archive, got %v", count)
+	}
+
+	dst, err := New("_obj/Maildir/dst", true)
+	if err != nil {
+		t.Fatalf("Can't create maildir: %v", err)
+	}
+	if err := dst.Import(NewTarReader(bytes.NewReader(buf.Bytes()))); err != nil {
+		t.Fatalf("Can't import maildir: %v", err)
+	}
+
+	keys, err := dst.Keys()
+	if err != nil {
+		t.Fatalf("Can't list keys: %v", err)
+	}
+	if len(keys) != 1 {
+		t.Fatalf("Expected 1 key, got %v", keys)
+	}
+
+	r, err := dst.Open(keys[0])
+	if err != nil {
+		t.Fatalf("Can't open imported mail: %v", err)
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Can't read imported mail: %v", err)
+	}
+	if !bytes.Equal(data, testData) {
+		t.Errorf("Read %#v, expected %#v", string(data), string(testData))
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2010 Simon Lipp.
+// Distributed under a BSD-like license. See COPYING for more
+// details
+
+package maildir
+
+import (
+	"io"
+	"os"
+	paths "path"
+)
+
+// DeliveryOptions controls how a message is delivered by CreateMailWithOptions
+// and CreateMailLinkWithOptions.
+type DeliveryOptions struct {
+	// Sync selects whether the delivered file, and the tmp/ and new/
+	// directories it passes through, are fsync'd before the call returns.
+	// Without it, a crash between the rename and the next directory flush
+	// can lose the message on file systems like ext4 or xfs. Set it to
+	// false to trade that guarantee for throughput on high-volume,
+	// best-effort deliveries.
+	Sync bool
+
+	// RFC822Size selects whether CreateMailWithOptions computes the
+	// message's RFC822 size (its size if every bare "\n" were a "\r\n") in
+	// the same pass as the copy, and records it in the delivered file name
+	// as a ",W=" field alongside the usual ",S=" one.
+	RFC822Size bool
+}
+
+// CreateMailWithOptions is like CreateMail, but lets the caller control
+// delivery options.
+func (m *Maildir) CreateMailWithOptions(data io.Reader, opts DeliveryOptions) (filename string, err error) {
+	return m.createMail(data, opts)
+}
+
+func (m *Maildir) createMail(data io.Reader, opts DeliveryOptions) (filename string, err error) {
+	basename, err := newBasename()
+	if err != nil {
+		return "", err
+	}
+
+	tmpname := paths.Join(m.Path, "tmp", basename)
+	file, err := m.fs.OpenFile(tmpname, os.O_RDWR|os.O_CREATE|os.O_TRUNC, m.perm)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	var w io.Writer = file
+	var counter *crlfSizeCounter
+	if opts.RFC822Size {
+		counter = &crlfSizeCounter{w: file}
+		w = counter
+	}
+
+	size, err := io.Copy(w, data)
+	if err != nil {
+		m.fs.Remove(tmpname)
+		return "", err
+	}
+
+	info := Info{Size: size}
+	if counter != nil {
+		info.RFC822Size = counter.rfc822
+	}
+
+	newname := paths.Join(m.Path, "new", info.Filename(basename))
+	if err := m.deliverTmp(tmpname, newname, opts); err != nil {
+		return "", err
+	}
+	return newname, nil
+}
+
+// CreateMailLink delivers the file at src into the maildir by hard-linking
+// it into tmp/ before renaming it into new/, falling back to copying its
+// content when src is on a different device than the maildir. This avoids
+// re-reading multi-MB payloads already on disk, e.g. during filter/sieve
+// style redelivery.
+func (m *Maildir) CreateMailLink(src string) (filename string, err error) {
+	return m.createMailLink(src, DeliveryOptions{Sync: true})
+}
+
+// CreateMailLinkWithOptions is like CreateMailLink, but lets the caller
+// control delivery options.
+func (m *Maildir) CreateMailLinkWithOptions(src string, opts DeliveryOptions) (filename string, err error) {
+	return m.createMailLink(src, opts)
+}
+
+func (m *Maildir) createMailLink(src string, opts DeliveryOptions) (filename string, err error) {
+	fi, err := m.fs.Stat(src)
+	if err != nil {
+		return "", err
+	}
+
+	basename, err := newBasename()
+	if err != nil {
+		return "", err
+	}
+
+	tmpname := paths.Join(m.Path, "tmp", basename)
+	if err := m.fs.Link(src, tmpname); err != nil {
+		if err := copyFile(m.fs, src, tmpname, m.perm); err != nil {
+			return "", err
+		}
+	}
+
+	newname := paths.Join(m.Path, "new", Info{Size: fi.Size()}.Filename(basename))
+	if err := m.deliverTmp(tmpname, newname, opts); err != nil {
+		return "", err
+	}
+	return newname, nil
+}
+
+// deliverTmp fsyncs (unless opts.Sync is false) and renames tmpname, already
+// in tmp/, into newname in new/, fsyncing the containing directories around
+// the rename so that a crash cannot lose the message.
+func (m *Maildir) deliverTmp(tmpname, newname string, opts DeliveryOptions) error {
+	if opts.Sync {
+		if err := m.fs.Sync(tmpname); err != nil {
+			m.fs.Remove(tmpname)
+			return err
+		}
+		if err := m.fs.Sync(paths.Join(m.Path, "tmp")); err != nil {
+			m.fs.Remove(tmpname)
+			return err
+		}
+	}
+
+	if err := m.fs.Rename(tmpname, newname); err != nil {
+		m.fs.Remove(tmpname)
+		return err
+	}
+
+	if opts.Sync {
+		if err := m.fs.Sync(paths.Join(m.Path, "new")); err != nil {
+			m.fs.Remove(newname)
+			return err
+		}
+	}
+
+	if err := changeOwner(m.fs, newname, m.uid, m.gid); err != nil {
+		// don't want to leave files with bad permissions
+		m.fs.Remove(newname)
+		return err
+	}
+
+	return nil
+}
+
+// copyFile copies the content of src into dst, creating dst with perm. It is
+// used as a fallback for CreateMailLink when src cannot be hard-linked (e.g.
+// because it is on a different device).
+func copyFile(fs FS, src, dst string, perm os.FileMode) error {
+	in, err := fs.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := fs.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
@@ -0,0 +1,76 @@
+package maildir
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestCreateMailWithOptionsNoSync(t *testing.T) {
+	if err := os.RemoveAll("_obj/Maildir"); err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll("_obj/Maildir")
+
+	maildir, err := New("_obj/Maildir", true)
+	if err != nil {
+		t.Fatalf("Can't create maildir: %v", err)
+	}
+
+	testData := []byte("Hello, world !")
+	fullName, err := maildir.CreateMailWithOptions(bytes.NewBuffer(testData), DeliveryOptions{Sync: false})
+	if err != nil {
+		t.Fatalf("Can't create mail: %v", err)
+	}
+
+	data, err := os.ReadFile(fullName)
+	if err != nil {
+		t.Fatalf("Can't read %v: %v", fullName, err)
+	}
+	if !bytes.Equal(data, testData) {
+		t.Errorf("Read %#v, expected %#v", string(data), string(testData))
+	}
+}
+
+func TestCreateMailLink(t *testing.T) {
+	if err := os.RemoveAll("_obj/Maildir"); err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll("_obj/Maildir")
+
+	maildir, err := New("_obj/Maildir", true)
+	if err != nil {
+		t.Fatalf("Can't create maildir: %v", err)
+	}
+
+	testData := []byte("Hello, world !")
+	srcFile, err := os.CreateTemp("", "go-maildir-test")
+	if err != nil {
+		t.Fatalf("Can't create source file: %v", err)
+	}
+	defer os.Remove(srcFile.Name())
+	if _, err := srcFile.Write(testData); err != nil {
+		t.Fatalf("Can't write source file: %v", err)
+	}
+	srcFile.Close()
+
+	fullName, err := maildir.CreateMailLink(srcFile.Name())
+	if err != nil {
+		t.Fatalf("Can't create mail link: %v", err)
+	}
+
+	f, err := os.Open(fullName)
+	if err != nil {
+		t.Fatalf("Can't open %v: %v", fullName, err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("Can't read %v: %v", fullName, err)
+	}
+	if !bytes.Equal(data, testData) {
+		t.Errorf("Read %#v, expected %#v", string(data), string(testData))
+	}
+}
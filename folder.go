@@ -0,0 +1,147 @@
+// Copyright 2010 Simon Lipp.
+// Distributed under a BSD-like license. See COPYING for more
+// details
+
+package maildir
+
+import (
+	"bytes"
+	"fmt"
+	paths "path"
+	"strings"
+	"unicode/utf16"
+)
+
+// Container represents a root maildir that may hold sub-folders, either in
+// the Maildir++ layout (dot-prefixed encoded names directly under the root,
+// e.g. ".Foo.Bar") or as a plain nested layout where sub-directories are
+// themselves maildirs.
+type Container struct {
+	Root *Maildir
+}
+
+// NewContainer wraps root as the base of a folder hierarchy.
+func NewContainer(root *Maildir) *Container {
+	return &Container{Root: root}
+}
+
+// isMaildir reports whether path has the three sub-directories required of
+// a maildir: new/, cur/ and tmp/.
+func isMaildir(fs FS, path string) bool {
+	for _, subdir := range []string{"new", "cur", "tmp"} {
+		fi, err := fs.Stat(paths.Join(path, subdir))
+		if err != nil || !fi.IsDir() {
+			return false
+		}
+	}
+	return true
+}
+
+// ListFolders returns every folder found in the container, in both the
+// Maildir++ and the plain nested layout.
+func (c *Container) ListFolders() ([]*Maildir, error) {
+	var folders []*Maildir
+	err := c.Walk(func(m *Maildir) error {
+		folders = append(folders, m)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+// Walk calls fn once for every folder found in the container. Directories
+// that are missing one of new/, cur/ or tmp/ (like ".notmuch" or "xapian")
+// are silently skipped.
+func (c *Container) Walk(fn func(*Maildir) error) error {
+	return walkDir(c.Root, fn)
+}
+
+func walkDir(root *Maildir, fn func(*Maildir) error) error {
+	entries, err := root.fs.ReadDir(root.Path)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || name == "new" || name == "cur" || name == "tmp" {
+			continue
+		}
+
+		path := paths.Join(root.Path, name)
+		if !isMaildir(root.fs, path) {
+			continue
+		}
+
+		child, err := newWithRawPath(root.fs, path, false, root.perm, root.uid, root.gid)
+		if err != nil {
+			return err
+		}
+		if err := fn(child); err != nil {
+			return err
+		}
+
+		// Maildir++ folders are already flat (nesting is expressed through
+		// dots in the name), only the plain layout needs recursion.
+		if !strings.HasPrefix(name, ".") {
+			if err := walkDir(child, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// DecodeName decodes a single path component previously produced by
+// encodeName, i.e. it is the inverse of the modified-UTF-7 encoding used to
+// build folder names.
+func DecodeName(encoded string) (string, error) {
+	var out bytes.Buffer
+
+	for i := 0; i < len(encoded); {
+		if encoded[i] != '&' {
+			out.WriteByte(encoded[i])
+			i++
+			continue
+		}
+
+		if i+1 < len(encoded) && encoded[i+1] == '-' {
+			out.WriteByte('&')
+			i += 2
+			continue
+		}
+
+		end := strings.IndexByte(encoded[i+1:], '-')
+		if end == -1 {
+			return "", fmt.Errorf("maildir: unterminated encoded sequence in %q", encoded)
+		}
+		end += i + 1
+
+		b64 := encoded[i+1 : end]
+		if pad := len(b64) % 4; pad != 0 {
+			b64 += strings.Repeat("=", 4-pad)
+		}
+
+		raw := make([]byte, maildirBase64.DecodedLen(len(b64)))
+		n, err := maildirBase64.Decode(raw, []byte(b64))
+		if err != nil {
+			return "", fmt.Errorf("maildir: invalid encoded sequence in %q: %w", encoded, err)
+		}
+		raw = raw[:n]
+		if len(raw)%2 != 0 {
+			return "", fmt.Errorf("maildir: invalid encoded sequence in %q", encoded)
+		}
+
+		units := make([]uint16, len(raw)/2)
+		for j := range units {
+			units[j] = uint16(raw[j*2])<<8 | uint16(raw[j*2+1])
+		}
+		out.WriteString(string(utf16.Decode(units)))
+
+		i = end + 1
+	}
+
+	return out.String(), nil
+}
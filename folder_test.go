@@ -0,0 +1,89 @@
+package maildir
+
+import (
+	"os"
+	"sort"
+	"testing"
+)
+
+func TestDecodeName(t *testing.T) {
+	for _, testData := range encodingTests {
+		decoded, err := DecodeName(testData.encoded)
+		if err != nil {
+			t.Errorf("Can't decode %v: %v", testData.encoded, err)
+			continue
+		}
+		if decoded != testData.decoded {
+			t.Errorf("DecodeName(%v) = %v, expected %v", testData.encoded, decoded, testData.decoded)
+		}
+	}
+}
+
+func TestContainerWalkMaildirPlusPlus(t *testing.T) {
+	if err := os.RemoveAll("_obj/Maildir"); err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll("_obj/Maildir")
+
+	root, err := New("_obj/Maildir", true)
+	if err != nil {
+		t.Fatalf("Can't create maildir: %v", err)
+	}
+	if _, err := root.Child("Foo", true); err != nil {
+		t.Fatalf("Can't create sub-maildir: %v", err)
+	}
+	if err := os.MkdirAll("_obj/Maildir/.notmuch", 0755); err != nil {
+		t.Fatalf("Can't create stray dir: %v", err)
+	}
+
+	folders, err := NewContainer(root).ListFolders()
+	if err != nil {
+		t.Fatalf("Can't list folders: %v", err)
+	}
+	if len(folders) != 1 || folders[0].Path != "_obj/Maildir/.Foo" {
+		t.Errorf("Expected [_obj/Maildir/.Foo], got %v", folders)
+	}
+}
+
+func TestContainerWalkPlainNested(t *testing.T) {
+	if err := os.RemoveAll("_obj/Maildir"); err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll("_obj/Maildir")
+
+	root, err := New("_obj/Maildir", true)
+	if err != nil {
+		t.Fatalf("Can't create maildir: %v", err)
+	}
+	if _, err := New("_obj/Maildir/Foo", true); err != nil {
+		t.Fatalf("Can't create sub-maildir: %v", err)
+	}
+	if _, err := New("_obj/Maildir/Foo/Bar", true); err != nil {
+		t.Fatalf("Can't create sub-sub-maildir: %v", err)
+	}
+	if err := os.MkdirAll("_obj/Maildir/xapian", 0755); err != nil {
+		t.Fatalf("Can't create stray dir: %v", err)
+	}
+
+	folders, err := NewContainer(root).ListFolders()
+	if err != nil {
+		t.Fatalf("Can't list folders: %v", err)
+	}
+
+	paths := make([]string, len(folders))
+	for i, f := range folders {
+		paths[i] = f.Path
+	}
+	sort.Strings(paths)
+
+	expected := []string{"_obj/Maildir/Foo", "_obj/Maildir/Foo/Bar"}
+	if len(paths) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, paths)
+	}
+	for i := range expected {
+		if paths[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, paths)
+			break
+		}
+	}
+}
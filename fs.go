@@ -0,0 +1,106 @@
+// Copyright 2010 Simon Lipp.
+// Distributed under a BSD-like license. See COPYING for more
+// details
+
+package maildir
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+// File is the subset of *os.File used by this package.
+type File interface {
+	io.Reader
+	io.Writer
+	io.Closer
+}
+
+// FS abstracts the filesystem operations Maildir needs, so a maildir can be
+// backed by something other than the local disk (an in-memory filesystem for
+// tests, an overlay filesystem, ...). OsFs preserves today's behavior of
+// talking to the local disk directly.
+type FS interface {
+	Open(name string) (File, error)
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	Mkdir(name string, perm os.FileMode) error
+	MkdirAll(path string, perm os.FileMode) error
+	Stat(name string) (os.FileInfo, error)
+	Rename(oldpath, newpath string) error
+	Remove(name string) error
+	// Link creates newname as a hard link to oldname. Implementations that
+	// have no notion of hard links may fall back to copying the content.
+	Link(oldname, newname string) error
+	Chown(name string, uid, gid int) error
+	Chmod(name string, mode os.FileMode) error
+	// Chtimes sets the access and modification times of name, as used by
+	// Import to restore the mtime recorded by Export.
+	Chtimes(name string, atime, mtime time.Time) error
+	// Sync flushes name to stable storage. name may be a file or a
+	// directory; implementations that cannot honor directory syncing (e.g.
+	// in-memory filesystems) may treat it as a no-op.
+	Sync(name string) error
+	// ReadDir returns the entries of the directory name, sorted by name.
+	ReadDir(name string) ([]os.DirEntry, error)
+}
+
+// OsFs implements FS on top of the local disk, using the os package.
+type OsFs struct{}
+
+func (OsFs) Open(name string) (File, error) {
+	return os.Open(name)
+}
+
+func (OsFs) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	return os.OpenFile(name, flag, perm)
+}
+
+func (OsFs) Mkdir(name string, perm os.FileMode) error {
+	return os.Mkdir(name, perm)
+}
+
+func (OsFs) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+func (OsFs) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (OsFs) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+func (OsFs) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func (OsFs) Link(oldname, newname string) error {
+	return os.Link(oldname, newname)
+}
+
+func (OsFs) Chown(name string, uid, gid int) error {
+	return os.Chown(name, uid, gid)
+}
+
+func (OsFs) Chmod(name string, mode os.FileMode) error {
+	return os.Chmod(name, mode)
+}
+
+func (OsFs) Chtimes(name string, atime, mtime time.Time) error {
+	return os.Chtimes(name, atime, mtime)
+}
+
+func (OsFs) Sync(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
+
+func (OsFs) ReadDir(name string) ([]os.DirEntry, error) {
+	return os.ReadDir(name)
+}
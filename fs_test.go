@@ -0,0 +1,318 @@
+package maildir
+
+import (
+	"bytes"
+	"io"
+	"os"
+	paths "path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memFS is a minimal in-memory FS implementation used to exercise NewWithFS
+// without touching the local disk.
+type memFS struct {
+	mu     sync.Mutex
+	files  map[string][]byte
+	dirs   map[string]bool
+	perms  map[string]os.FileMode
+	mtimes map[string]time.Time
+}
+
+func newMemFS() *memFS {
+	return &memFS{
+		files:  make(map[string][]byte),
+		dirs:   make(map[string]bool),
+		perms:  make(map[string]os.FileMode),
+		mtimes: make(map[string]time.Time),
+	}
+}
+
+type memFile struct {
+	fs    *memFS
+	name  string
+	data  []byte
+	pos   int
+	write bool
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	if f.pos >= len(f.data) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.data[f.pos:])
+	f.pos += n
+	return n, nil
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	f.data = append(f.data, p...)
+	if f.write {
+		f.fs.mu.Lock()
+		f.fs.files[f.name] = f.data
+		f.fs.mu.Unlock()
+	}
+	return len(p), nil
+}
+
+func (f *memFile) Close() error {
+	return nil
+}
+
+type memFileInfo struct {
+	name  string
+	size  int64
+	dir   bool
+	mode  os.FileMode
+	mtime time.Time
+}
+
+func (fi *memFileInfo) Name() string { return fi.name }
+func (fi *memFileInfo) Size() int64  { return fi.size }
+func (fi *memFileInfo) Mode() os.FileMode {
+	if fi.dir {
+		return fi.mode | os.ModeDir
+	}
+	return fi.mode
+}
+func (fi *memFileInfo) ModTime() time.Time { return fi.mtime }
+func (fi *memFileInfo) IsDir() bool        { return fi.dir }
+func (fi *memFileInfo) Sys() interface{}   { return nil }
+
+type memDirEntry struct {
+	name string
+	dir  bool
+}
+
+func (e *memDirEntry) Name() string { return e.name }
+func (e *memDirEntry) IsDir() bool  { return e.dir }
+func (e *memDirEntry) Type() os.FileMode {
+	if e.dir {
+		return os.ModeDir
+	}
+	return 0
+}
+func (e *memDirEntry) Info() (os.FileInfo, error) {
+	return &memFileInfo{name: e.name, dir: e.dir}, nil
+}
+
+func (fs *memFS) Open(name string) (File, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	data, ok := fs.files[name]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return &memFile{fs: fs, name: name, data: append([]byte(nil), data...)}, nil
+}
+
+func (fs *memFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	data, exists := fs.files[name]
+	if !exists && flag&os.O_CREATE == 0 {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	if !exists {
+		fs.perms[name] = perm
+	}
+
+	f := &memFile{fs: fs, name: name, write: true}
+	if flag&os.O_TRUNC == 0 {
+		f.data = append([]byte(nil), data...)
+	}
+	return f, nil
+}
+
+func (fs *memFS) Mkdir(name string, perm os.FileMode) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.dirs[name] = true
+	fs.perms[name] = perm
+	return nil
+}
+
+func (fs *memFS) MkdirAll(path string, perm os.FileMode) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	cur := ""
+	for _, part := range strings.Split(strings.Trim(path, "/"), "/") {
+		if part == "" {
+			continue
+		}
+		if cur == "" {
+			cur = part
+		} else {
+			cur = cur + "/" + part
+		}
+		fs.dirs[cur] = true
+		if _, ok := fs.perms[cur]; !ok {
+			fs.perms[cur] = perm
+		}
+	}
+	return nil
+}
+
+func (fs *memFS) Stat(name string) (os.FileInfo, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	name = strings.TrimSuffix(name, "/")
+	if fs.dirs[name] {
+		return &memFileInfo{name: paths.Base(name), dir: true, mode: fs.perms[name], mtime: fs.mtimes[name]}, nil
+	}
+	if data, ok := fs.files[name]; ok {
+		return &memFileInfo{name: paths.Base(name), size: int64(len(data)), mode: fs.perms[name], mtime: fs.mtimes[name]}, nil
+	}
+	return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+}
+
+func (fs *memFS) Rename(oldpath, newpath string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if data, ok := fs.files[oldpath]; ok {
+		fs.files[newpath] = data
+		fs.perms[newpath] = fs.perms[oldpath]
+		delete(fs.files, oldpath)
+		delete(fs.perms, oldpath)
+		return nil
+	}
+	if fs.dirs[oldpath] {
+		fs.dirs[newpath] = true
+		delete(fs.dirs, oldpath)
+		return nil
+	}
+	return &os.PathError{Op: "rename", Path: oldpath, Err: os.ErrNotExist}
+}
+
+func (fs *memFS) Link(oldname, newname string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	data, ok := fs.files[oldname]
+	if !ok {
+		return &os.PathError{Op: "link", Path: oldname, Err: os.ErrNotExist}
+	}
+	fs.files[newname] = data
+	fs.perms[newname] = fs.perms[oldname]
+	return nil
+}
+
+func (fs *memFS) Remove(name string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if _, ok := fs.files[name]; ok {
+		delete(fs.files, name)
+		delete(fs.perms, name)
+		return nil
+	}
+	if fs.dirs[name] {
+		delete(fs.dirs, name)
+		return nil
+	}
+	return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+}
+
+func (fs *memFS) Chown(name string, uid, gid int) error { return nil }
+
+func (fs *memFS) Chtimes(name string, atime, mtime time.Time) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.mtimes[name] = mtime
+	return nil
+}
+
+func (fs *memFS) Chmod(name string, mode os.FileMode) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.perms[name] = mode
+	return nil
+}
+
+func (fs *memFS) Sync(name string) error { return nil }
+
+func (fs *memFS) ReadDir(name string) ([]os.DirEntry, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	prefix := strings.TrimSuffix(name, "/") + "/"
+	seen := make(map[string]bool)
+	var entries []os.DirEntry
+
+	for p := range fs.files {
+		if rest, ok := directChild(p, prefix); ok && !seen[rest] {
+			seen[rest] = true
+			entries = append(entries, &memDirEntry{name: rest})
+		}
+	}
+	for p := range fs.dirs {
+		if rest, ok := directChild(p, prefix); ok && !seen[rest] {
+			seen[rest] = true
+			entries = append(entries, &memDirEntry{name: rest, dir: true})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func directChild(path, prefix string) (string, bool) {
+	if !strings.HasPrefix(path, prefix) {
+		return "", false
+	}
+	rest := path[len(prefix):]
+	if rest == "" || strings.Contains(rest, "/") {
+		return "", false
+	}
+	return rest, true
+}
+
+func TestNewWithFS(t *testing.T) {
+	fs := newMemFS()
+
+	md, err := NewWithFS(fs, "Maildir", true)
+	if err != nil {
+		t.Fatalf("Can't create maildir: %v", err)
+	}
+
+	for _, subdir := range []string{"cur", "tmp", "new"} {
+		if fi, err := fs.Stat("Maildir/" + subdir); err != nil || !fi.IsDir() {
+			t.Errorf("Can't stat %v of in-memory maildir: %v", subdir, err)
+		}
+	}
+
+	testData := []byte("Hello, world !")
+	fullName, err := md.CreateMail(bytes.NewBuffer(testData))
+	if err != nil {
+		t.Fatalf("Can't create mail: %v", err)
+	}
+
+	keys, err := md.Keys()
+	if err != nil {
+		t.Fatalf("Can't list keys: %v", err)
+	}
+	if len(keys) != 1 || paths.Join(md.Path, "new", keys[0]) != fullName {
+		t.Errorf("Expected keys [%v], got %v", paths.Base(fullName), keys)
+	}
+
+	r, err := md.Open(keys[0])
+	if err != nil {
+		t.Fatalf("Can't open mail: %v", err)
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Can't read mail: %v", err)
+	}
+	if !bytes.Equal(data, testData) {
+		t.Errorf("Read %#v, expected %#v", string(data), string(testData))
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2010 Simon Lipp.
+// Distributed under a BSD-like license. See COPYING for more
+// details
+
+package maildir
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Info holds the well-known comma-separated fields found in a maildir file
+// name, as used by Courier (S=), Dovecot (S=, W=) and IMAP-sync tools such
+// as mbsync or OfflineIMAP (U=): the octet size, the RFC822 size (the size
+// the message would have if every bare "\n" were a "\r\n"), the UID it was
+// last seen with, and its status flags.
+type Info struct {
+	Size       int64
+	RFC822Size int64
+	UID        uint32
+	Flags      []Flag
+}
+
+// ParseInfo parses the fields and flags encoded in a message file name.
+// Unknown comma-separated fields are ignored, so that this package keeps
+// working with fields it doesn't know about.
+func ParseInfo(name string) (Info, error) {
+	unique, flagsPart, hasFlags := splitInfo(name)
+
+	var info Info
+	if hasFlags {
+		info.Flags = make([]Flag, len(flagsPart))
+		for i := 0; i < len(flagsPart); i++ {
+			info.Flags[i] = Flag(flagsPart[i])
+		}
+	}
+
+	fields := strings.Split(unique, ",")
+	for _, field := range fields[1:] {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key, value := parts[0], parts[1]
+
+		switch key {
+		case "S":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return Info{}, fmt.Errorf("maildir: invalid S field in %q: %w", name, err)
+			}
+			info.Size = n
+		case "W":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return Info{}, fmt.Errorf("maildir: invalid W field in %q: %w", name, err)
+			}
+			info.RFC822Size = n
+		case "U":
+			n, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return Info{}, fmt.Errorf("maildir: invalid U field in %q: %w", name, err)
+			}
+			info.UID = uint32(n)
+		}
+	}
+
+	return info, nil
+}
+
+// Filename builds the file name for a message whose unique part (the
+// "timestamp.MmicroPpid_counter.hostname" token generated by CreateMail) is
+// base, encoding Size, and RFC822Size and UID when non-zero, followed by the
+// flags info suffix when Flags is non-nil.
+func (info Info) Filename(base string) string {
+	name := fmt.Sprintf("%v,S=%v", base, info.Size)
+	if info.RFC822Size != 0 {
+		name += fmt.Sprintf(",W=%v", info.RFC822Size)
+	}
+	if info.UID != 0 {
+		name += fmt.Sprintf(",U=%v", info.UID)
+	}
+
+	if info.Flags != nil {
+		sorted := make([]byte, len(info.Flags))
+		for i, flag := range info.Flags {
+			sorted[i] = byte(flag)
+		}
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		name += infoSeparator + string(sorted)
+	}
+
+	return name
+}
+
+// crlfSizeCounter wraps a writer and, in addition to forwarding writes
+// unchanged, counts the RFC822 size the data would have if every bare "\n"
+// were normalized to "\r\n". It does this in a single pass over the data
+// being written, with no buffering of its own.
+type crlfSizeCounter struct {
+	w        io.Writer
+	rfc822   int64
+	lastByte byte
+}
+
+func (c *crlfSizeCounter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+
+	for i := 0; i < n; i++ {
+		prev := c.lastByte
+		if i > 0 {
+			prev = p[i-1]
+		}
+		if p[i] == '\n' && prev != '\r' {
+			c.rfc822++
+		}
+		c.rfc822++
+	}
+	if n > 0 {
+		c.lastByte = p[n-1]
+	}
+
+	return n, err
+}
@@ -0,0 +1,69 @@
+package maildir
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseInfoAndFilename(t *testing.T) {
+	name := "1414059977.M183716P20182.hostname,S=1000,W=1010,U=123:2,FS"
+
+	info, err := ParseInfo(name)
+	if err != nil {
+		t.Fatalf("Can't parse info: %v", err)
+	}
+
+	expected := Info{Size: 1000, RFC822Size: 1010, UID: 123, Flags: []Flag{FlagFlagged, FlagSeen}}
+	if !reflect.DeepEqual(info, expected) {
+		t.Errorf("ParseInfo(%v) = %+v, expected %+v", name, info, expected)
+	}
+
+	built := info.Filename("1414059977.M183716P20182.hostname")
+	if built != name {
+		t.Errorf("Filename() = %v, expected %v", built, name)
+	}
+}
+
+func TestParseInfoNoFields(t *testing.T) {
+	name := "1414059977.M183716P20182.hostname"
+
+	info, err := ParseInfo(name)
+	if err != nil {
+		t.Fatalf("Can't parse info: %v", err)
+	}
+	if !reflect.DeepEqual(info, Info{}) {
+		t.Errorf("Expected zero Info, got %+v", info)
+	}
+}
+
+func TestCreateMailWithRFC822Size(t *testing.T) {
+	if err := os.RemoveAll("_obj/Maildir"); err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll("_obj/Maildir")
+
+	maildir, err := New("_obj/Maildir", true)
+	if err != nil {
+		t.Fatalf("Can't create maildir: %v", err)
+	}
+
+	testData := []byte("Subject: hi\nHello,\nworld !\n")
+	fullName, err := maildir.CreateMailWithOptions(bytes.NewBuffer(testData), DeliveryOptions{Sync: true, RFC822Size: true})
+	if err != nil {
+		t.Fatalf("Can't create mail: %v", err)
+	}
+
+	info, err := ParseInfo(fullName)
+	if err != nil {
+		t.Fatalf("Can't parse info of %v: %v", fullName, err)
+	}
+	if info.Size != int64(len(testData)) {
+		t.Errorf("Expected size %v, got %v", len(testData), info.Size)
+	}
+	// every "\n" is bare, so RFC822Size adds one byte per line
+	if expected := int64(len(testData)) + 3; info.RFC822Size != expected {
+		t.Errorf("Expected RFC822 size %v, got %v", expected, info.RFC822Size)
+	}
+}
@@ -35,6 +35,7 @@ type Maildir struct {
 	// The root path ends with a /, others don't, so we can have
 	// the child of a maildir just with path + "." + encodedChildName.
 	Path     string
+	fs       FS
 	perm     os.FileMode
 	uid, gid int
 }
@@ -44,7 +45,7 @@ const DoNotSetOwner = -1
 // Default file perms for files. For directories u+x will be added
 const DefaultFilePerm = 0600
 
-func newWithRawPath(path string, create bool, perm os.FileMode, uid, gid int) (m *Maildir, err error) {
+func newWithRawPath(fs FS, path string, create bool, perm os.FileMode, uid, gid int) (m *Maildir, err error) {
 	// start counter if needed, preventing race condition
 	counterInit.Do(func() {
 		counter = make(chan uint)
@@ -59,35 +60,35 @@ func newWithRawPath(path string, create bool, perm os.FileMode, uid, gid int) (m
 	dirPerm := os.FileMode(perm | ((perm & 0444) >> 2))
 
 	// Create if needed
-	if _, err := os.Stat(path); create && err != nil && os.IsNotExist(err) {
-		if err := os.MkdirAll(path, dirPerm); err != nil {
+	if _, err := fs.Stat(path); create && err != nil && os.IsNotExist(err) {
+		if err := fs.MkdirAll(path, dirPerm); err != nil {
 			return nil, err
 		}
-		if err = changeOwner(path, uid, gid); err != nil {
+		if err = changeOwner(fs, path, uid, gid); err != nil {
 			return nil, err
 		}
 	} else if err != nil {
 		return nil, err
 	}
 	if create {
-		if err := createSubFolders(path, dirPerm, uid, gid); err != nil {
+		if err := createSubFolders(fs, path, dirPerm, uid, gid); err != nil {
 			return nil, err
 		}
 	}
 
-	return &Maildir{path, perm, uid, gid}, nil
+	return &Maildir{path, fs, perm, uid, gid}, nil
 }
 
 // createSubFolders creates the tmp/, cur/ and new/ sub-folders folders
-func createSubFolders(path string, dirPerm os.FileMode, uid, gid int) error {
+func createSubFolders(fs FS, path string, dirPerm os.FileMode, uid, gid int) error {
 	// check that the sub-folders exist, if not create them
 	for _, subdir := range []string{"tmp", "cur", "new"} {
 		ps := paths.Join(path, subdir)
-		if _, err := os.Stat(ps); os.IsNotExist(err) {
-			if err := os.Mkdir(ps, dirPerm); err != nil {
+		if _, err := fs.Stat(ps); os.IsNotExist(err) {
+			if err := fs.Mkdir(ps, dirPerm); err != nil {
 				return err
 			}
-			if err := changeOwner(ps, uid, gid); err != nil {
+			if err := changeOwner(fs, ps, uid, gid); err != nil {
 				return err
 			}
 		} else if err != nil {
@@ -99,8 +100,7 @@ func createSubFolders(path string, dirPerm os.FileMode, uid, gid int) error {
 
 // Open a maildir. If create is true and the maildir does not exist, create it.
 func New(path string, create bool) (m *Maildir, err error) {
-	path = normalizePath(path)
-	return newWithRawPath(path, create, DefaultFilePerm, DoNotSetOwner, DoNotSetOwner)
+	return NewWithFS(OsFs{}, path, create)
 }
 
 // Same as New, but ability to control permissions
@@ -109,7 +109,15 @@ func New(path string, create bool) (m *Maildir, err error) {
 // uid and gid are for os.Chown, pass DoNotSetOwner constant to ignore.
 func NewWithPerm(path string, create bool, perm os.FileMode, uid, gid int) (m *Maildir, err error) {
 	path = normalizePath(path)
-	return newWithRawPath(path, create, perm, uid, gid)
+	return newWithRawPath(OsFs{}, path, create, perm, uid, gid)
+}
+
+// NewWithFS is like New, but lets the caller supply a different FS
+// implementation (e.g. an in-memory filesystem for tests) instead of talking
+// to the local disk directly.
+func NewWithFS(fs FS, path string, create bool) (m *Maildir, err error) {
+	path = normalizePath(path)
+	return newWithRawPath(fs, path, create, DefaultFilePerm, DoNotSetOwner, DoNotSetOwner)
 }
 
 // normalizePath ensures that path is not empty and ends with a /
@@ -126,7 +134,7 @@ func normalizePath(p string) string {
 // exist, create it.
 func (m *Maildir) Child(name string, create bool) (*Maildir, error) {
 	encodedPath := m.encodeName(name)
-	return newWithRawPath(encodedPath.String(), create, m.perm, m.uid, m.gid)
+	return newWithRawPath(m.fs, encodedPath.String(), create, m.perm, m.uid, m.gid)
 }
 
 // encodeName encodes non valid characters according to mailbox folder nameing spec
@@ -149,49 +157,26 @@ func (m *Maildir) encodeName(name string) *bytes.Buffer {
 
 // Write a mail to the maildir folder. The data is not encoded or compressed in any way.
 func (m *Maildir) CreateMail(data io.Reader) (filename string, err error) {
-	hostname, err := os.Hostname()
-	if err != nil {
-		return "", err
-	}
-
-	basename := fmt.Sprintf("%v.M%vP%v_%v.%v", time.Now().Unix(), time.Now().Nanosecond()/1000, pid, <-counter, hostname)
-	tmpname := paths.Join(m.Path, "tmp", basename)
-	file, err := os.OpenFile(tmpname, os.O_RDWR|os.O_CREATE|os.O_TRUNC, m.perm)
-	if err != nil {
-		return "", err
-	}
-	defer file.Close()
-	size, err := io.Copy(file, data)
-	if err != nil {
-		os.Remove(tmpname)
-		return "", err
-	}
-	file.Sync()
-
-	newname := paths.Join(m.Path, "new", fmt.Sprintf("%v,S=%v", basename, size))
-	err = os.Rename(tmpname, newname)
-	if err != nil {
-		os.Remove(tmpname)
-		return "", err
-	}
+	return m.createMail(data, DeliveryOptions{Sync: true})
+}
 
-	err = changeOwner(newname, m.gid, m.uid)
+// newBasename generates a new unique file name, as documented at
+// http://www.courier-mta.org/maildir.html
+func newBasename() (string, error) {
+	hostname, err := os.Hostname()
 	if err != nil {
-		// don't want to leave files with bad permissions
-		os.Remove(newname)
 		return "", err
 	}
-
-	return newname, nil
+	return fmt.Sprintf("%v.M%vP%v_%v.%v", time.Now().Unix(), time.Now().Nanosecond()/1000, pid, <-counter, hostname), nil
 }
 
 // changeOwner changes the owner of the path.
 // No changes will be made if uid or guid are set to const DoNotSetOwner
-func changeOwner(path string, uid, gid int) error {
+func changeOwner(fs FS, path string, uid, gid int) error {
 	if uid == DoNotSetOwner || gid == DoNotSetOwner {
 		return nil
 	}
-	return os.Chown(path, uid, gid)
+	return fs.Chown(path, uid, gid)
 }
 
 // Valid (valid = has not to be escaped) chars =
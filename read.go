@@ -0,0 +1,191 @@
+// Copyright 2010 Simon Lipp.
+// Distributed under a BSD-like license. See COPYING for more
+// details
+
+package maildir
+
+import (
+	"fmt"
+	"io"
+	"os"
+	paths "path"
+	"sort"
+	"strings"
+)
+
+// infoSeparator introduces the info suffix of a maildir file name, as
+// documented at http://www.courier-mta.org/maildir.html
+const infoSeparator = ":2,"
+
+// Flag is one of the single-letter maildir status flags that can appear in
+// the info suffix of a message file name.
+type Flag byte
+
+const (
+	FlagPassed  Flag = 'P'
+	FlagReplied Flag = 'R'
+	FlagSeen    Flag = 'S'
+	FlagTrashed Flag = 'T'
+	FlagDraft   Flag = 'D'
+	FlagFlagged Flag = 'F'
+)
+
+// splitInfo splits a message file name into its unique part and, if present,
+// its info suffix.
+func splitInfo(name string) (unique, info string, hasInfo bool) {
+	i := strings.Index(name, infoSeparator)
+	if i == -1 {
+		return name, "", false
+	}
+	return name[:i], name[i+len(infoSeparator):], true
+}
+
+// List returns the names of the messages present in dir, which must be
+// either "new" or "cur".
+func (m *Maildir) List(dir string) ([]string, error) {
+	if dir != "new" && dir != "cur" {
+		return nil, fmt.Errorf("maildir: List: invalid subdirectory %q", dir)
+	}
+
+	entries, err := m.fs.ReadDir(paths.Join(m.Path, dir))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		keys = append(keys, entry.Name())
+	}
+	return keys, nil
+}
+
+// Keys returns the keys of every message in the maildir, in both new/ and cur/.
+func (m *Maildir) Keys() ([]string, error) {
+	var keys []string
+	for _, dir := range []string{"new", "cur"} {
+		names, err := m.List(dir)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, names...)
+	}
+	return keys, nil
+}
+
+// findMessage locates the subdirectory (new or cur) holding the message
+// identified by key.
+func (m *Maildir) findMessage(key string) (subdir string, err error) {
+	for _, dir := range []string{"cur", "new"} {
+		_, err := m.fs.Stat(paths.Join(m.Path, dir, key))
+		if err == nil {
+			return dir, nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("maildir: no such message %q", key)
+}
+
+// Open opens the message identified by key for reading.
+func (m *Maildir) Open(key string) (io.ReadCloser, error) {
+	subdir, err := m.findMessage(key)
+	if err != nil {
+		return nil, err
+	}
+	return m.fs.Open(paths.Join(m.Path, subdir, key))
+}
+
+// Remove deletes the message identified by key.
+func (m *Maildir) Remove(key string) error {
+	subdir, err := m.findMessage(key)
+	if err != nil {
+		return err
+	}
+	return m.fs.Remove(paths.Join(m.Path, subdir, key))
+}
+
+// Move moves the message identified by key from m to dst, keeping the same
+// new/cur subdirectory and file name.
+func (m *Maildir) Move(key string, dst *Maildir) error {
+	subdir, err := m.findMessage(key)
+	if err != nil {
+		return err
+	}
+
+	src := paths.Join(m.Path, subdir, key)
+	dstPath := paths.Join(dst.Path, subdir, key)
+	if err := m.fs.Rename(src, dstPath); err != nil {
+		return err
+	}
+	return changeOwner(dst.fs, dstPath, dst.uid, dst.gid)
+}
+
+// Flags returns the status flags currently set on the message identified by key.
+func (m *Maildir) Flags(key string) ([]Flag, error) {
+	if _, err := m.findMessage(key); err != nil {
+		return nil, err
+	}
+
+	_, info, hasInfo := splitInfo(key)
+	if !hasInfo {
+		return nil, nil
+	}
+
+	flags := make([]Flag, len(info))
+	for i := 0; i < len(info); i++ {
+		flags[i] = Flag(info[i])
+	}
+	return flags, nil
+}
+
+// SetFlags updates the info suffix of the message identified by key with
+// flags, sorted in ASCII order as required by the maildir spec, and returns
+// the message's new key.
+func (m *Maildir) SetFlags(key string, flags []Flag) (newKey string, err error) {
+	subdir, err := m.findMessage(key)
+	if err != nil {
+		return "", err
+	}
+
+	unique, _, _ := splitInfo(key)
+
+	sorted := make([]byte, len(flags))
+	for i, flag := range flags {
+		sorted[i] = byte(flag)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	newKey = unique + infoSeparator + string(sorted)
+	if newKey == key {
+		return key, nil
+	}
+
+	err = m.fs.Rename(paths.Join(m.Path, subdir, key), paths.Join(m.Path, subdir, newKey))
+	if err != nil {
+		return "", err
+	}
+	return newKey, nil
+}
+
+// Deliver moves the message identified by key from new/ to cur/, adding the
+// info suffix (with no flags set) if it isn't present yet, and returns the
+// message's new key.
+func (m *Maildir) Deliver(key string) (newKey string, err error) {
+	if _, err := m.fs.Stat(paths.Join(m.Path, "new", key)); err != nil {
+		return "", err
+	}
+
+	newKey = key
+	if _, _, hasInfo := splitInfo(key); !hasInfo {
+		newKey = key + infoSeparator
+	}
+
+	err = m.fs.Rename(paths.Join(m.Path, "new", key), paths.Join(m.Path, "cur", newKey))
+	if err != nil {
+		return "", err
+	}
+	return newKey, nil
+}
@@ -0,0 +1,109 @@
+package maildir
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestReadWriteFlags(t *testing.T) {
+	if err := os.RemoveAll("_obj/Maildir"); err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll("_obj/Maildir")
+
+	maildir, err := New("_obj/Maildir", true)
+	if err != nil {
+		t.Fatalf("Can't create maildir: %v", err)
+	}
+
+	fullName, err := maildir.CreateMail(bytes.NewBufferString("Hello, world !"))
+	if err != nil {
+		t.Fatalf("Can't create mail: %v", err)
+	}
+	key := fullName[len("_obj/Maildir/new/"):]
+
+	keys, err := maildir.Keys()
+	if err != nil {
+		t.Fatalf("Can't list keys: %v", err)
+	}
+	if len(keys) != 1 || keys[0] != key {
+		t.Errorf("Expected keys [%v], got %v", key, keys)
+	}
+
+	flags, err := maildir.Flags(key)
+	if err != nil {
+		t.Fatalf("Can't get flags: %v", err)
+	}
+	if len(flags) != 0 {
+		t.Errorf("Expected no flags on a freshly delivered mail, got %v", flags)
+	}
+
+	key, err = maildir.Deliver(key)
+	if err != nil {
+		t.Fatalf("Can't deliver mail: %v", err)
+	}
+
+	newKey, err := maildir.SetFlags(key, []Flag{FlagSeen, FlagFlagged})
+	if err != nil {
+		t.Fatalf("Can't set flags: %v", err)
+	}
+	key = newKey
+
+	flags, err = maildir.Flags(key)
+	if err != nil {
+		t.Fatalf("Can't get flags: %v", err)
+	}
+	if len(flags) != 2 || flags[0] != FlagFlagged || flags[1] != FlagSeen {
+		t.Errorf("Expected flags [F S] (sorted), got %v", flags)
+	}
+
+	r, err := maildir.Open(key)
+	if err != nil {
+		t.Fatalf("Can't open mail: %v", err)
+	}
+	defer r.Close()
+
+	if err := maildir.Remove(key); err != nil {
+		t.Fatalf("Can't remove mail: %v", err)
+	}
+
+	if _, err := maildir.Flags(key); err == nil {
+		t.Error("Expected an error when getting flags of a removed message")
+	}
+}
+
+func TestMove(t *testing.T) {
+	if err := os.RemoveAll("_obj/Maildir"); err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll("_obj/Maildir")
+
+	src, err := New("_obj/Maildir/src", true)
+	if err != nil {
+		t.Fatalf("Can't create maildir: %v", err)
+	}
+	dst, err := New("_obj/Maildir/dst", true)
+	if err != nil {
+		t.Fatalf("Can't create maildir: %v", err)
+	}
+
+	fullName, err := src.CreateMail(bytes.NewBufferString("Hello, world !"))
+	if err != nil {
+		t.Fatalf("Can't create mail: %v", err)
+	}
+	key := fullName[len("_obj/Maildir/src/new/"):]
+
+	if err := src.Move(key, dst); err != nil {
+		t.Fatalf("Can't move mail: %v", err)
+	}
+
+	if _, err := src.Open(key); err == nil {
+		t.Error("Expected mail to be gone from src")
+	}
+	r, err := dst.Open(key)
+	if err != nil {
+		t.Fatalf("Expected mail to be present in dst: %v", err)
+	}
+	r.Close()
+}